@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/inconshreveable/log15"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+const (
+	// hookDispatcherMarker identifies a pre-commit hook as one installed
+	// by platform-starter, so it's never mistaken for a user's own hook.
+	hookDispatcherMarker = "# platform-starter managed pre-commit dispatcher"
+	hooksSubdir          = "pre-commit.d"
+	chainedHookName      = "pre-commit.local"
+)
+
+// gitHooksDir returns the directory git looks for hooks in, honoring
+// core.hooksPath when configured (as set up by tools like husky or
+// lefthook) instead of always assuming .git/hooks.
+func gitHooksDir(root string) string {
+	out, err := exec.Command("git", "-C", root, "config", "core.hooksPath").Output()
+	if err == nil {
+		if path := strings.TrimSpace(string(out)); path != "" {
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(root, path)
+			}
+			return path
+		}
+	}
+
+	return filepath.Join(root, ".git", "hooks")
+}
+
+// installPreCommitHook installs the platform-starter pre-commit hook
+// without clobbering whatever was already there: an existing,
+// unmanaged pre-commit hook is kept as pre-commit.local and chained
+// from the dispatcher this installs. Other hooks platform-starter adds
+// in the future can drop their scripts into pre-commit.d/ and the
+// dispatcher will run them too.
+func installPreCommitHook(root string, dryRun bool) error {
+	hooksDir := gitHooksDir(root)
+	hookPath := filepath.Join(hooksDir, "pre-commit")
+	dispatchDir := filepath.Join(hooksDir, hooksSubdir)
+
+	if dryRun {
+		log15.Info("dry-run: would install pre-commit hook", "dir", hooksDir)
+		return nil
+	}
+
+	if err := os.MkdirAll(dispatchDir, 0755); err != nil {
+		return err
+	}
+
+	if exists(hookPath) && !isManagedHook(hookPath) {
+		log15.Info("Chaining existing pre-commit hook", "path", hookPath)
+		chained := filepath.Join(hooksDir, chainedHookName)
+		if err := os.Rename(hookPath, chained); err != nil {
+			return fmt.Errorf("unable to chain existing hook: %s", err)
+		}
+		if err := os.Chmod(chained, 0755); err != nil {
+			return err
+		}
+	}
+
+	// Written with an explicit executable mode: the dispatcher only
+	// runs entries passing `[ -x "$hook" ]`, and the embedded asset's
+	// own mode isn't guaranteed to be executable.
+	managedPath := filepath.Join(dispatchDir, "platform-starter")
+	if err := ioutil.WriteFile(managedPath, precommitHook.asset.bytes, 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(hookPath, []byte(dispatcherScript()), 0755)
+}
+
+func dispatcherScript() string {
+	return strings.Join([]string{
+		"#!/bin/sh",
+		hookDispatcherMarker,
+		fmt.Sprintf(`local="$(dirname "$0")/%s"`, chainedHookName),
+		`if [ -x "$local" ]; then "$local" "$@" || exit $?; fi`,
+		fmt.Sprintf(`for hook in "$(dirname "$0")/%s"/*; do`, hooksSubdir),
+		`  [ -x "$hook" ] && { "$hook" "$@" || exit $?; }`,
+		"done",
+		"",
+	}, "\n")
+}
+
+func isManagedHook(path string) bool {
+	data, err := ioutil.ReadFile(path)
+	return err == nil && strings.Contains(string(data), hookDispatcherMarker)
+}
+
+// uninstallPreCommitHook removes the managed dispatcher and its
+// pre-commit.d directory, restoring whatever hook it had chained.
+func uninstallPreCommitHook(root string) error {
+	hooksDir := gitHooksDir(root)
+	hookPath := filepath.Join(hooksDir, "pre-commit")
+	dispatchDir := filepath.Join(hooksDir, hooksSubdir)
+	chained := filepath.Join(hooksDir, chainedHookName)
+
+	if !isManagedHook(hookPath) {
+		return fmt.Errorf("no platform-starter managed hook found at %s", hookPath)
+	}
+
+	if err := os.Remove(hookPath); err != nil {
+		return err
+	}
+
+	if exists(chained) {
+		log15.Info("Restoring previous pre-commit hook")
+		if err := os.Rename(chained, hookPath); err != nil {
+			return err
+		}
+	}
+
+	return os.RemoveAll(dispatchDir)
+}
+
+func uninstallHooksCommand() cli.Command {
+	return cli.Command{
+		Name:  "uninstall-hooks",
+		Usage: "remove the platform-starter managed pre-commit hook, restoring any hook it replaced",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "dir",
+				Usage: "directory of the project",
+				Value: ".",
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			dir, err := filepath.Abs(ctx.String("dir"))
+			if err != nil {
+				return err
+			}
+			return uninstallPreCommitHook(dir)
+		},
+	}
+}
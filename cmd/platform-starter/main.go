@@ -29,6 +29,34 @@ func main() {
 			Name:  "npm",
 			Usage: "forces the usage of npm for installing dependencies",
 		},
+		cli.StringFlag{
+			Name:  "template-repo",
+			Usage: "git repository or .zip archive URL to fetch template files from, instead of the embedded ones",
+		},
+		cli.StringFlag{
+			Name:  "template-ref",
+			Usage: "git ref (branch, tag or commit) to use with --template-repo",
+		},
+		cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "print the commands that would run instead of executing them",
+		},
+		cli.BoolFlag{
+			Name:  "force",
+			Usage: "overwrite existing files without prompting",
+		},
+		cli.BoolFlag{
+			Name:  "no-prompt",
+			Usage: "never prompt; skip existing files instead (CI-safe)",
+		},
+	}
+	app.Commands = []cli.Command{
+		newCommand(),
+		templatesCommand(),
+		validateCommand(),
+		syncCommand(),
+		uninstallHooksCommand(),
+		restoreCommand(),
 	}
 
 	app.Run(os.Args)
@@ -37,17 +65,24 @@ func main() {
 type requirement struct {
 	pkg    string
 	binary bool
+	// source is the install-source backend to use (npm, yarn, pnpm,
+	// brew, apt, go). Empty means auto-detect, as before.
+	source string
+	// version is the requirement's minimum version: an installed binary
+	// whose `--version` output reports at least this version is accepted,
+	// otherwise this exact version is installed.
+	version string
 }
 
 var requirements = []requirement{
-	{"csscomb", true},
-	{"editorconfig-tools", true},
-	{"eslint", true},
-	{"prettier", true},
-	{"svgo", true},
-	{"eslint-plugin-prettier", false},
-	{"eslint-config-airbnb-base", false},
-	{"eslint-plugin-import", false},
+	{pkg: "csscomb", binary: true},
+	{pkg: "editorconfig-tools", binary: true},
+	{pkg: "eslint", binary: true},
+	{pkg: "prettier", binary: true},
+	{pkg: "svgo", binary: true},
+	{pkg: "eslint-plugin-prettier"},
+	{pkg: "eslint-config-airbnb-base"},
+	{pkg: "eslint-plugin-import"},
 }
 
 type file struct {
@@ -85,66 +120,172 @@ var gitignore = file{
 func run(ctx *cli.Context) error {
 	log15.Info("Starting platform-starter")
 
-	log15.Info("Installing requirements...")
-	for _, r := range requirements {
-		ensureInstalled(r, ctx.Bool("npm"))
+	dir, err := filepath.Abs(ctx.String("dir"))
+	if err != nil {
+		log15.Crit("unable to get absolute path for directory", "dir", ctx.String("dir"), "err", err)
+		os.Exit(1)
+	}
+
+	tmpl := defaultTemplate
+	if path, ok := manifestPath(dir); ok {
+		m, err := loadManifest(path)
+		if err != nil {
+			log15.Crit("unable to load manifest", "path", path, "err", err)
+			os.Exit(1)
+		}
+
+		tmpl, err = m.toTemplate(defaultTemplate.Name)
+		if err != nil {
+			log15.Crit("invalid manifest", "path", path, "err", err)
+			os.Exit(1)
+		}
 	}
 
+	return apply(resolveTemplate(ctx, tmpl), dir, ctx.Bool("npm"), ctx.Bool("dry-run"), ctx.Bool("force"), ctx.Bool("no-prompt"))
+}
+
+// resolveTemplate picks the TemplateSource tmpl's files come from: the
+// files embedded in tmpl itself, unless --template-repo was given, in
+// which case they're replaced with those fetched from the remote source
+// (see TemplateSource in source.go).
+func resolveTemplate(ctx *cli.Context, tmpl *Template) *Template {
+	var source TemplateSource = embeddedSource{tmpl: tmpl}
+
+	repo := ctx.GlobalString("template-repo")
+	if repo == "" {
+		repo = ctx.String("template-repo")
+	}
+	if repo != "" {
+		ref := ctx.GlobalString("template-ref")
+		if ref == "" {
+			ref = ctx.String("template-ref")
+		}
+		source = remoteSource(repo, ref)
+	}
+
+	files, err := source.Files()
+	if err != nil {
+		log15.Crit("unable to resolve template files", "repo", repo, "err", err)
+		os.Exit(1)
+	}
+
+	resolved := *tmpl
+	resolved.Files = files
+	return &resolved
+}
+
+// apply installs the requirements and copies the files declared by tmpl
+// into dir, initializing a git repository and pre-commit hook if
+// needed. The whole operation is transactional: anything it overwrites
+// is backed up first, and every failure - including one from
+// installing a requirement - is rolled back via the deferred
+// tx.rollback() below rather than leaving the project half configured.
+func apply(tmpl *Template, dir string, npm, dryRun, force, noPrompt bool) (err error) {
 	root, err := os.Getwd()
 	if err != nil {
 		log15.Crit("unable to get current working directory", "err", err)
 		os.Exit(1)
 	}
 
-	dir := ctx.String("dir")
 	dir, err = filepath.Abs(dir)
 	if err != nil {
 		log15.Crit("unable to get absolute path for directory", "dir", dir, "err", err)
 		os.Exit(1)
 	}
 
+	tx, err := newTransaction(dir, dryRun)
+	if err != nil {
+		log15.Crit("unable to start transaction", "err", err)
+		os.Exit(1)
+	}
+
+	defer func() {
+		if err != nil {
+			log15.Warn("apply failed, rolling back...", "err", err)
+			if rerr := tx.rollback(); rerr != nil {
+				log15.Crit("rollback failed, project may be left half configured", "err", rerr)
+				return
+			}
+			tx.prune()
+			return
+		}
+
+		if len(tx.backedUp) == 0 {
+			tx.prune()
+		}
+	}()
+
+	log15.Info("Installing requirements...")
+	for _, r := range tmpl.Requirements {
+		if err = ensureInstalled(r, npm, dryRun); err != nil {
+			return fmt.Errorf("unable to install `%s`: %s", r.pkg, err)
+		}
+	}
+
+	if err = applyFiles(tx, tmpl, root, dir, force, noPrompt, dryRun); err != nil {
+		return err
+	}
+
+	if tmpl.PostInit != nil {
+		log15.Info("Running post-init hook...")
+		if err = tmpl.PostInit(dir); err != nil {
+			return fmt.Errorf("post-init hook failed: %s", err)
+		}
+	}
+
+	log15.Info("Everything ready!")
+	return nil
+}
+
+// applyFiles performs the file-copying, git and hook steps of apply.
+func applyFiles(tx *transaction, tmpl *Template, root, dir string, force, noPrompt, dryRun bool) error {
 	if !exists(filepath.Join(dir, ".gitignore")) {
 		log15.Info("Adding default .gitignore")
-		if err := copyFile(root, dir, gitignore); err != nil {
-			log15.Crit("error copying gitignore", "err", err)
-			os.Exit(1)
+		if err := copyFile(tx, root, dir, gitignore, force, noPrompt, dryRun); err != nil {
+			return fmt.Errorf("error copying gitignore: %s", err)
 		}
 	}
 
 	log15.Info("Copying assets...")
-	for _, f := range files {
+	for _, f := range tmpl.Files {
 		log15.Info("Copying", "file", filepath.Join(f.dest...))
-		if err := copyFile(root, dir, f); err != nil {
-			log15.Crit("error copying asset", "file", f.path(root, dir), "err", err)
-			os.Exit(1)
+		if err := copyFile(tx, root, dir, f, force, noPrompt, dryRun); err != nil {
+			return fmt.Errorf("error copying asset %s: %s", f.path(root, dir), err)
 		}
 	}
 
 	if !isDir(filepath.Join(root, ".git")) {
-		if err := initializeGitRepo(); err != nil {
-			log15.Crit("unable to initialize git repo", "err", err)
-			os.Exit(1)
+		if err := initializeGitRepo(tx, root, dryRun); err != nil {
+			return fmt.Errorf("unable to initialize git repo: %s", err)
 		}
 	}
 
 	log15.Info("Installing pre-commit hook...")
-	if err := copyFile(root, dir, precommitHook); err != nil {
-		log15.Crit("error copying pre-commit hook", "err", err)
-		os.Exit(1)
+	if err := installPreCommitHook(root, dryRun); err != nil {
+		return fmt.Errorf("error installing pre-commit hook: %s", err)
 	}
 
-	log15.Info("Everything ready!")
 	return nil
 }
 
-func initializeGitRepo() error {
+func initializeGitRepo(tx *transaction, root string, dryRun bool) error {
 	log15.Warn("Current directory is not a git repository.")
 	log15.Info("Initializing git repository...")
+	if dryRun {
+		log15.Info("dry-run: would run git init/add/commit")
+		return nil
+	}
+
 	if err := cmd("git", "init"); err != nil {
 		return err
 	}
+	tx.gitRoot = root
 
-	if err := cmd("git", "add", "-A"); err != nil {
+	// Excludes every .platform-starter/ directory (this tool's own backup
+	// directory, which lives under --dir and so not necessarily at the
+	// repository root) so a backed-up file never ends up swept into the
+	// project's first commit.
+	if err := cmd("git", "add", "-A", "--", ".", ":(exclude,glob)**/.platform-starter/**"); err != nil {
 		return fmt.Errorf("unable to add files to repo: %s", err)
 	}
 
@@ -155,71 +296,84 @@ func initializeGitRepo() error {
 	return nil
 }
 
-func ensureInstalled(r requirement, npm bool) {
-	if r.binary {
-		_, err := exec.LookPath(r.pkg)
-		if err != nil {
-			log15.Warn(fmt.Sprintf("Looks like `%s` is not installed", r.pkg))
-			if err := install(r.pkg, npm); err != nil {
-				log15.Crit(fmt.Sprintf("Unable to install `%s`", r.pkg), "err", err)
-				os.Exit(1)
-			}
-		}
-	} else {
-		if err := install(r.pkg, npm); err != nil {
-			log15.Crit(fmt.Sprintf("Unable to install `%s`", r.pkg), "err", err)
-			os.Exit(1)
-		}
+func ensureInstalled(r requirement, npm, dryRun bool) error {
+	pm, err := resolvePackageManager(r, npm)
+	if err != nil {
+		return err
 	}
-}
 
-func install(program string, npmForce bool) error {
-	log15.Info(fmt.Sprintf("Installing %s...", program))
-	if !npmForce {
-		yarn, err := exec.LookPath("yarn")
-		if err == nil {
-			return cmd(yarn, "global", "add", program)
+	if r.binary {
+		installed, err := pm.Installed(r.pkg, r.version)
+		if err == nil && installed {
+			return nil
 		}
-
-		log15.Warn("yarn is not installed, resorting to install using npm")
+		log15.Warn(fmt.Sprintf("Looks like `%s` is not installed", r.pkg))
 	}
 
-	npm, err := exec.LookPath("npm")
-	if err == nil {
-		return cmd(npm, "install", "-g", program)
-	}
-
-	log15.Crit("npm and yarn are not installed. Aborting process.")
-	os.Exit(1)
-	return nil
+	log15.Info(fmt.Sprintf("Installing %s via %s...", r.pkg, pm.Name()))
+	return pm.Install(r.pkg, r.version, dryRun)
 }
 
-func copyFile(root, pwd string, file file) error {
+// copyFile writes file into place, backing up via tx whatever it
+// overwrites so the transaction can be rolled back later. In dry-run
+// mode nothing is written, backed up or removed - it only logs what
+// would have happened.
+func copyFile(tx *transaction, root, pwd string, file file, force, noPrompt, dryRun bool) error {
 	path := file.path(root, pwd)
 	_, err := os.Stat(path)
 	if err != nil && !os.IsNotExist(err) {
 		return err
 	} else if err == nil {
 		log15.Warn(fmt.Sprintf("file %s already exists", filepath.Join(file.dest...)))
-		if !prompt.Confirm("Do you want to overwrite it?") {
+		if !confirmOverwrite(force, noPrompt) {
 			log15.Warn("Skipped copy of file.", "file", filepath.Join(file.dest...))
 			return nil
 		}
 
+		if dryRun {
+			log15.Info("dry-run: would overwrite", "file", filepath.Join(file.dest...))
+			return nil
+		}
+
+		if err := tx.backup(path); err != nil {
+			return fmt.Errorf("unable to back up file: %s", err)
+		}
+
 		if err := os.Remove(path); err != nil {
 			return fmt.Errorf("unable to remove file: %s", err)
 		}
+	} else {
+		if dryRun {
+			log15.Info("dry-run: would write", "file", filepath.Join(file.dest...))
+			return nil
+		}
+		tx.trackCreated(path)
 	}
 
 	return ioutil.WriteFile(path, file.asset.bytes, file.asset.info.Mode())
 }
 
+// confirmOverwrite decides whether an existing file should be
+// overwritten: --force always says yes, --no-prompt always says no
+// (the safe default for CI), and otherwise the user is asked.
+func confirmOverwrite(force, noPrompt bool) bool {
+	if force {
+		return true
+	}
+	if noPrompt {
+		return false
+	}
+	return prompt.Confirm("Do you want to overwrite it?")
+}
+
 func cmd(bin string, args ...string) error {
 	cmd := exec.Command(bin, args...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	cmd.Start()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
 	return cmd.Wait()
 }
 
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	toml "github.com/BurntSushi/toml"
+	"github.com/inconshreveable/log15"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+// manifestNameTOML and manifestNameJSON are the files platform-starter
+// looks for in a project directory to override its embedded defaults.
+// Both extensions are
+// supported; the TOML one is tried first.
+const (
+	manifestNameTOML = "platform-starter.toml"
+	manifestNameJSON = "platform-starter.json"
+)
+
+// ManifestRequirement declares a tool platform-starter should make sure
+// is installed before writing any files.
+type ManifestRequirement struct {
+	Name          string `toml:"name" json:"name"`
+	Binary        bool   `toml:"binary" json:"binary"`
+	MinVersion    string `toml:"min_version" json:"minVersion"`
+	InstallSource string `toml:"install_source" json:"installSource"`
+}
+
+// ManifestFile declares a file to render into the target project.
+// Source is either the path of an embedded asset (see assetsByName) or,
+// when it doesn't match one, a path relative to the manifest itself.
+type ManifestFile struct {
+	Source      string            `toml:"source" json:"source"`
+	Destination string            `toml:"destination" json:"destination"`
+	FromRoot    bool              `toml:"from_root" json:"fromRoot"`
+	Variables   map[string]string `toml:"variables" json:"variables"`
+}
+
+// ManifestHooks holds the shell commands a manifest lists as pre and
+// post hooks. They are parsed with the manifest, but toTemplate does not
+// carry them into the Template, so neither apply nor sync runs them.
+type ManifestHooks struct {
+	Pre  []string `toml:"pre" json:"pre"`
+	Post []string `toml:"post" json:"post"`
+}
+
+// Manifest is the declarative, on-disk equivalent of a Template: it
+// describes the requirements, files and hooks of a project without
+// requiring platform-starter to be recompiled.
+type Manifest struct {
+	Requirements []ManifestRequirement `toml:"requirement" json:"requirements"`
+	Files        []ManifestFile        `toml:"file" json:"files"`
+	Hooks        ManifestHooks         `toml:"hooks" json:"hooks"`
+
+	dir string
+}
+
+// assetsByName maps the path of an embedded asset to the go-bindata
+// accessor that returns it, so manifests can reference embedded assets
+// by name instead of duplicating their contents.
+var assetsByName = map[string]func() (*asset, error){
+	"config/csscomb.json": configCsscombJson,
+	"config/eslintrc.js":  configEslintrcJs,
+	"config/editorconfig": configEditorconfig,
+	"config/gitignore":    configGitignore,
+	"hooks/pre-commit":    hooksPreCommit,
+}
+
+// manifestPath returns the manifest file present in dir, if any.
+func manifestPath(dir string) (string, bool) {
+	for _, name := range []string{manifestNameTOML, manifestNameJSON} {
+		path := filepath.Join(dir, name)
+		if exists(path) {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// loadManifest reads and parses the manifest at path. The format is
+// chosen based on the file extension.
+func loadManifest(path string) (*Manifest, error) {
+	var m Manifest
+	if filepath.Ext(path) == ".json" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("invalid manifest %s: %s", path, err)
+		}
+	} else {
+		if _, err := toml.DecodeFile(path, &m); err != nil {
+			return nil, fmt.Errorf("invalid manifest %s: %s", path, err)
+		}
+	}
+
+	m.dir = filepath.Dir(path)
+	return &m, nil
+}
+
+// toTemplate resolves every ManifestFile into a file ready to be
+// applied, rendering template variables where given.
+func (m *Manifest) toTemplate(name string) (*Template, error) {
+	reqs := make([]requirement, len(m.Requirements))
+	for i, r := range m.Requirements {
+		reqs[i] = requirement{pkg: r.Name, binary: r.Binary, source: r.InstallSource, version: r.MinVersion}
+	}
+
+	fs := make([]file, len(m.Files))
+	for i, mf := range m.Files {
+		a, err := m.resolveAsset(mf)
+		if err != nil {
+			return nil, fmt.Errorf("file %s: %s", mf.Source, err)
+		}
+
+		fs[i] = file{asset: a, dest: mkPath(mf.Destination), fromRoot: mf.FromRoot}
+	}
+
+	return &Template{Name: name, Requirements: reqs, Files: fs}, nil
+}
+
+func (m *Manifest) resolveAsset(mf ManifestFile) (*asset, error) {
+	if fn, ok := assetsByName[mf.Source]; ok {
+		a, err := fn()
+		if err != nil {
+			return nil, err
+		}
+		return renderAsset(a, mf.Variables)
+	}
+
+	path := mf.Source
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(m.dir, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return renderAsset(&asset{bytes: data, info: fi}, mf.Variables)
+}
+
+// renderAsset applies vars as text/template variables to a's contents,
+// returning a untouched when no variables are declared.
+func renderAsset(a *asset, vars map[string]string) (*asset, error) {
+	if len(vars) == 0 {
+		return a, nil
+	}
+
+	tpl, err := template.New("file").Parse(string(a.bytes))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, vars); err != nil {
+		return nil, err
+	}
+
+	return &asset{bytes: buf.Bytes(), info: a.info}, nil
+}
+
+func validateCommand() cli.Command {
+	return cli.Command{
+		Name:  "validate",
+		Usage: "validate the platform-starter manifest of a project",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "dir",
+				Usage: "directory of the project to validate",
+				Value: ".",
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			dir, err := filepath.Abs(ctx.String("dir"))
+			if err != nil {
+				return err
+			}
+
+			path, ok := manifestPath(dir)
+			if !ok {
+				return fmt.Errorf("no %s or %s found in %s", manifestNameTOML, manifestNameJSON, dir)
+			}
+
+			m, err := loadManifest(path)
+			if err != nil {
+				return err
+			}
+
+			if _, err := m.toTemplate("manifest"); err != nil {
+				return err
+			}
+
+			log15.Info("Manifest is valid", "path", path)
+			return nil
+		},
+	}
+}
+
+func syncCommand() cli.Command {
+	return cli.Command{
+		Name:  "sync",
+		Usage: "re-apply the project's manifest",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "dir",
+				Usage: "directory of the project to sync",
+				Value: ".",
+			},
+			cli.BoolFlag{
+				Name:  "npm",
+				Usage: "forces the usage of npm for installing dependencies",
+			},
+			cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "print the commands that would run instead of executing them",
+			},
+			cli.BoolFlag{
+				Name:  "force",
+				Usage: "overwrite existing files without prompting",
+			},
+			cli.BoolFlag{
+				Name:  "no-prompt",
+				Usage: "never prompt; skip existing files instead (CI-safe)",
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			dir, err := filepath.Abs(ctx.String("dir"))
+			if err != nil {
+				return err
+			}
+
+			path, ok := manifestPath(dir)
+			if !ok {
+				return fmt.Errorf("no %s or %s found in %s, nothing to sync", manifestNameTOML, manifestNameJSON, dir)
+			}
+
+			m, err := loadManifest(path)
+			if err != nil {
+				return err
+			}
+
+			tmpl, err := m.toTemplate("manifest")
+			if err != nil {
+				return err
+			}
+
+			return apply(tmpl, dir, ctx.Bool("npm"), ctx.Bool("dry-run"), ctx.Bool("force"), ctx.Bool("no-prompt"))
+		},
+	}
+}
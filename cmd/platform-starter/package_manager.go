@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/inconshreveable/log15"
+)
+
+// PackageManager installs a single package, optionally pinned to a
+// specific version.
+type PackageManager interface {
+	// Name is the backend's binary name, e.g. "npm", "yarn", "brew",
+	// "go". It matches the install-source key in packageManagers except
+	// for apt, whose binary is "apt-get".
+	Name() string
+	// Available reports whether the backend binary is on the PATH.
+	Available() bool
+	// Installed reports whether pkg is already installed, matching
+	// version when one is given.
+	Installed(pkg, version string) (bool, error)
+	// Install installs pkg, pinned to version when one is given.
+	Install(pkg, version string, dryRun bool) error
+}
+
+var packageManagers = map[string]PackageManager{
+	"yarn": execPackageManager{name: "yarn", installArgs: []string{"global", "add"}, pin: "@"},
+	"npm":  execPackageManager{name: "npm", installArgs: []string{"install", "-g"}, pin: "@"},
+	"pnpm": execPackageManager{name: "pnpm", installArgs: []string{"add", "-g"}, pin: "@"},
+	"brew": execPackageManager{name: "brew", installArgs: []string{"install"}, pin: "@"},
+	"apt":  execPackageManager{name: "apt-get", installArgs: []string{"install", "-y"}, pin: "="},
+	"go":   execPackageManager{name: "go", installArgs: []string{"install"}, pin: "@"},
+}
+
+// execPackageManager is a PackageManager backed by a CLI binary that
+// takes the package (optionally pinned) as its last argument.
+type execPackageManager struct {
+	name        string
+	installArgs []string
+	// pin is the separator used to pin a version, e.g. "@" for
+	// `npm install pkg@1.2.3` or `go install pkg@v1.2.3`. Empty means
+	// this backend doesn't support pinning on the command line.
+	pin string
+}
+
+func (m execPackageManager) Name() string { return m.name }
+
+func (m execPackageManager) Available() bool {
+	_, err := exec.LookPath(m.name)
+	return err == nil
+}
+
+func (m execPackageManager) Installed(pkg, version string) (bool, error) {
+	if _, err := exec.LookPath(pkg); err != nil {
+		return false, nil
+	}
+	return binaryVersionMatches(pkg, version)
+}
+
+func (m execPackageManager) Install(pkg, version string, dryRun bool) error {
+	bin, err := exec.LookPath(m.name)
+	if err != nil {
+		return fmt.Errorf("%s is not installed", m.name)
+	}
+
+	args := append(append([]string{}, m.installArgs...), m.pinnedPackage(pkg, version))
+	if dryRun {
+		log15.Info("dry-run: would execute", "cmd", strings.Join(append([]string{bin}, args...), " "))
+		return nil
+	}
+
+	return cmd(bin, args...)
+}
+
+func (m execPackageManager) pinnedPackage(pkg, version string) string {
+	if version == "" || m.pin == "" {
+		return pkg
+	}
+	return pkg + m.pin + version
+}
+
+var versionPattern = regexp.MustCompile(`\d+(\.\d+)*`)
+
+// parseVersion extracts the first dotted-numeric version number found
+// in s (e.g. a binary's `--version` output, or a manifest's min-version
+// string) and returns its components as integers.
+func parseVersion(s string) ([]int, bool) {
+	m := versionPattern.FindString(s)
+	if m == "" {
+		return nil, false
+	}
+
+	parts := strings.Split(m, ".")
+	nums := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, false
+		}
+		nums[i] = n
+	}
+
+	return nums, true
+}
+
+// compareVersions returns -1, 0 or 1 as a is less than, equal to or
+// greater than b, treating a missing trailing component as 0 (so 8.57
+// equals 8.57.0).
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// binaryVersionMatches runs `bin --version` and reports whether its
+// version is at least wanted (the requirement's min-version), using a
+// numeric comparison rather than a substring match so 8.57.1 satisfies
+// a minimum of 8.57.0 and an installed 0.1.2 doesn't spuriously satisfy
+// a minimum of 1.2. An empty wanted version always matches; a bin that
+// fails to run, or whose --version output (or wanted itself) can't be
+// parsed as a version, doesn't.
+func binaryVersionMatches(bin, wanted string) (bool, error) {
+	if wanted == "" {
+		return true, nil
+	}
+
+	out, err := exec.Command(bin, "--version").CombinedOutput()
+	if err != nil {
+		return false, nil
+	}
+
+	installed, ok := parseVersion(string(out))
+	if !ok {
+		return false, nil
+	}
+
+	want, ok := parseVersion(wanted)
+	if !ok {
+		return false, nil
+	}
+
+	return compareVersions(installed, want) >= 0, nil
+}
+
+// resolvePackageManager picks the backend to use for a requirement: its
+// explicit install-source when given, otherwise the first available of
+// yarn/npm, unless npmForce asks for npm directly.
+func resolvePackageManager(r requirement, npmForce bool) (PackageManager, error) {
+	if r.source != "" {
+		pm, ok := packageManagers[r.source]
+		if !ok {
+			return nil, fmt.Errorf("unknown install-source %q", r.source)
+		}
+		if !pm.Available() {
+			return nil, fmt.Errorf("%s is not installed", pm.Name())
+		}
+		return pm, nil
+	}
+
+	if !npmForce {
+		if yarn := packageManagers["yarn"]; yarn.Available() {
+			return yarn, nil
+		}
+		log15.Warn("yarn is not installed, resorting to install using npm")
+	}
+
+	npm := packageManagers["npm"]
+	if !npm.Available() {
+		return nil, fmt.Errorf("npm and yarn are not installed")
+	}
+
+	return npm, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []int
+		ok   bool
+	}{
+		{"8.57.1", []int{8, 57, 1}, true},
+		{"v1.2.3", []int{1, 2, 3}, true},
+		{"eslint 8.57.0 (bundled)", []int{8, 57, 0}, true},
+		{"1.2", []int{1, 2}, true},
+		{"no version here", nil, false},
+		{"", nil, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseVersion(tt.in)
+		if ok != tt.ok {
+			t.Errorf("parseVersion(%q) ok = %v, want %v", tt.in, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("parseVersion(%q) = %v, want %v", tt.in, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("parseVersion(%q) = %v, want %v", tt.in, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b []int
+		want int
+	}{
+		{[]int{8, 57, 1}, []int{8, 57, 0}, 1},
+		{[]int{8, 57, 0}, []int{8, 57, 1}, -1},
+		{[]int{8, 57}, []int{8, 57, 0}, 0},
+		{[]int{1, 2, 0}, []int{1, 2}, 0},
+		{[]int{11, 2, 0}, []int{1, 2}, 1},
+		{[]int{1, 2}, []int{11, 2, 0}, -1},
+	}
+
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%v, %v) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
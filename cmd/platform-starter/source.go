@@ -0,0 +1,312 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/inconshreveable/log15"
+)
+
+// TemplateSource knows how to produce the set of files for a template,
+// regardless of where those files actually live.
+type TemplateSource interface {
+	Files() ([]file, error)
+}
+
+// embeddedSource serves the files bundled into the platform-starter
+// binary via go-bindata. It is the source used by every template in
+// templateRegistry unless --template-repo overrides it.
+type embeddedSource struct {
+	tmpl *Template
+}
+
+func (s embeddedSource) Files() ([]file, error) {
+	return s.tmpl.Files, nil
+}
+
+// gitSource fetches a template tree from a git repository, caching the
+// clone under ~/.platform-starter/cache/<repo>@<ref>.
+type gitSource struct {
+	repo string
+	ref  string
+}
+
+func (s gitSource) Files() ([]file, error) {
+	dir, err := cacheDirFor(s.repo, s.ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isDir(dir) {
+		log15.Info("Cloning template repository...", "repo", s.repo, "ref", s.ref)
+		if err := cloneTemplateRepoToCache(s.repo, s.ref, dir); err != nil {
+			return nil, fmt.Errorf("unable to clone %s: %s", s.repo, err)
+		}
+	}
+
+	return filesFromDir(dir)
+}
+
+// cloneTemplateRepoToCache clones repo into a scratch directory and only
+// moves it to dir once ref has been checked out successfully, so a
+// checkout failure can never leave dir cached at the wrong ref - the
+// next run would otherwise see dir already exists and serve it as-is.
+func cloneTemplateRepoToCache(repo, ref, dir string) error {
+	parent := filepath.Dir(dir)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempDir(parent, ".clone-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := cloneTemplateRepo(repo, ref, tmp); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, dir)
+}
+
+// cloneTemplateRepo clones repo into dir and checks out ref. When ref is
+// empty, a shallow clone of the repository's default branch is enough.
+// Otherwise the ref may be a branch, tag or arbitrary commit SHA, which
+// --depth 1 --branch can't express, so the full history is cloned and
+// ref is checked out afterwards.
+func cloneTemplateRepo(repo, ref, dir string) error {
+	url := gitCloneURL(repo)
+	if ref == "" {
+		return cmd("git", "clone", "--depth", "1", url, dir)
+	}
+
+	if err := cmd("git", "clone", url, dir); err != nil {
+		return err
+	}
+
+	return cmd("git", "-C", dir, "checkout", ref)
+}
+
+func gitCloneURL(repo string) string {
+	if strings.Contains(repo, "://") || strings.HasPrefix(repo, "git@") {
+		return repo
+	}
+	return fmt.Sprintf("https://%s.git", repo)
+}
+
+// httpSource downloads a zip archive of a template tree, caching the
+// extracted contents under ~/.platform-starter/cache/<url>@<ref>.
+type httpSource struct {
+	url string
+	ref string
+}
+
+func (s httpSource) Files() ([]file, error) {
+	dir, err := cacheDirFor(s.url, s.ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isDir(dir) {
+		log15.Info("Downloading template archive...", "url", s.url)
+		if err := downloadAndExtract(s.url, dir); err != nil {
+			return nil, err
+		}
+	}
+
+	return filesFromDir(dir)
+}
+
+func downloadAndExtract(archiveURL, dest string) error {
+	resp, err := http.Get(archiveURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	tmp, err := ioutil.TempFile("", "platform-starter-archive-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		return err
+	}
+
+	r, err := zip.OpenReader(tmp.Name())
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	// GitHub-style zipballs wrap every entry in a top-level
+	// "<repo>-<ref>/" directory; strip it so files land at the root of
+	// dest instead of one level down.
+	prefix := commonZipPrefix(r.File)
+
+	for _, f := range r.File {
+		name := strings.TrimPrefix(f.Name, prefix)
+		if name == "" {
+			continue
+		}
+
+		path := filepath.Join(dest, name)
+		if !isWithinDir(dest, path) {
+			return fmt.Errorf("archive entry %q escapes destination directory", f.Name)
+		}
+
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(path, f.Mode()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+
+		if err := extractZipFile(f, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// commonZipPrefix returns the shared top-level directory of every entry
+// in files (e.g. "repo-main/" for a GitHub codeload zipball), or "" when
+// the entries don't share one.
+func commonZipPrefix(files []*zip.File) string {
+	if len(files) == 0 {
+		return ""
+	}
+
+	parts := strings.SplitN(files[0].Name, "/", 2)
+	if len(parts) < 2 {
+		return ""
+	}
+	prefix := parts[0] + "/"
+
+	for _, f := range files[1:] {
+		if !strings.HasPrefix(f.Name, prefix) {
+			return ""
+		}
+	}
+
+	return prefix
+}
+
+// isWithinDir reports whether path resolves to somewhere under dir,
+// guarding against zip-slip entries such as "../../etc/passwd".
+func isWithinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func extractZipFile(f *zip.File, dest string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, rc)
+	return err
+}
+
+// skipFromRemoteTemplate lists entries that never belong in a copied
+// template tree: git's internal metadata and the manifest the target
+// project may already carry.
+var skipFromRemoteTemplate = map[string]bool{
+	".git":           true,
+	manifestNameTOML: true,
+	manifestNameJSON: true,
+}
+
+// filesFromDir turns every regular file under dir into a file ready to
+// be copied with copyFile, rooted at the project's working directory.
+func filesFromDir(dir string) ([]file, error) {
+	var result []file
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if skipFromRemoteTemplate[fi.Name()] {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if fi.IsDir() {
+			return nil
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		result = append(result, file{
+			asset: &asset{bytes: data, info: fi},
+			dest:  mkPath(strings.Split(rel, string(filepath.Separator))...),
+		})
+		return nil
+	})
+
+	return result, err
+}
+
+// cacheDirFor returns the cache directory for a given remote template,
+// under ~/.platform-starter/cache/<repo>@<ref>.
+func cacheDirFor(repo, ref string) (string, error) {
+	home := os.Getenv("HOME")
+	if home == "" {
+		return "", fmt.Errorf("unable to determine home directory")
+	}
+
+	name := strings.NewReplacer("/", "-", ":", "-").Replace(repo)
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	return filepath.Join(home, ".platform-starter", "cache", fmt.Sprintf("%s@%s", name, ref)), nil
+}
+
+// remoteSource picks the right TemplateSource implementation for repo,
+// which may be a git remote (the default) or an http(s) URL pointing at
+// a zip archive. An empty ref means "the repository's default branch",
+// resolved by cloneTemplateRepo rather than guessed here.
+func remoteSource(repo, ref string) TemplateSource {
+	if u, err := url.Parse(repo); err == nil && (u.Scheme == "http" || u.Scheme == "https") && strings.HasSuffix(u.Path, ".zip") {
+		return httpSource{url: repo, ref: ref}
+	}
+
+	return gitSource{repo: repo, ref: ref}
+}
@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestIsWithinDir(t *testing.T) {
+	tests := []struct {
+		dir, path string
+		want      bool
+	}{
+		{"/tmp/cache", "/tmp/cache/file.txt", true},
+		{"/tmp/cache", "/tmp/cache/sub/file.txt", true},
+		{"/tmp/cache", "/tmp/cache", true},
+		{"/tmp/cache", "/tmp/other/file.txt", false},
+		{"/tmp/cache", "/tmp/cache-evil/file.txt", false},
+		{"/tmp/cache", "/tmp/cache/../../etc/passwd", false},
+	}
+
+	for _, tt := range tests {
+		if got := isWithinDir(tt.dir, tt.path); got != tt.want {
+			t.Errorf("isWithinDir(%q, %q) = %v, want %v", tt.dir, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+
+	prompt "github.com/segmentio/go-prompt"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+// Template describes a project scaffold: the tools it requires, the
+// files it writes into the target directory and an optional hook run
+// once those files are in place.
+type Template struct {
+	Name         string
+	Requirements []requirement
+	Files        []file
+	PostInit     func(dir string) error
+}
+
+// templateRegistry holds every template platform-starter knows how to
+// scaffold. The frontend template is the original, hardcoded behaviour
+// of platform-starter and remains the default when no template is given.
+var templateRegistry = []*Template{
+	{
+		Name:         "frontend",
+		Requirements: requirements,
+		Files:        files,
+	},
+	{
+		Name: "backend",
+		Requirements: []requirement{
+			{pkg: "editorconfig-tools", binary: true},
+		},
+		Files: []file{
+			{mustAsset(configEditorconfig()), mkPath(".editorconfig"), true},
+		},
+	},
+	{
+		Name: "library",
+		Requirements: []requirement{
+			{pkg: "editorconfig-tools", binary: true},
+		},
+		Files: []file{
+			{mustAsset(configEditorconfig()), mkPath(".editorconfig"), true},
+		},
+	},
+	{
+		Name: "plugin",
+		Requirements: []requirement{
+			{pkg: "editorconfig-tools", binary: true},
+		},
+		Files: []file{
+			{mustAsset(configEditorconfig()), mkPath(".editorconfig"), true},
+		},
+	},
+}
+
+var defaultTemplate = templateRegistry[0]
+
+func templateByName(name string) (*Template, bool) {
+	for _, t := range templateRegistry {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+func templateNames() []string {
+	names := make([]string, len(templateRegistry))
+	for i, t := range templateRegistry {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// templatesCommand lists the available templates.
+func templatesCommand() cli.Command {
+	return cli.Command{
+		Name:  "templates",
+		Usage: "list the available project templates",
+		Action: func(ctx *cli.Context) error {
+			for _, name := range templateNames() {
+				fmt.Println(name)
+			}
+			return nil
+		},
+	}
+}
+
+// newCommand scaffolds a project from a template, prompting the user to
+// pick one interactively when none is given on the command line.
+func newCommand() cli.Command {
+	return cli.Command{
+		Name:      "new",
+		Usage:     "scaffold a new project from a template",
+		ArgsUsage: "[template]",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "dir",
+				Usage: "directory to initialize",
+				Value: ".",
+			},
+			cli.BoolFlag{
+				Name:  "npm",
+				Usage: "forces the usage of npm for installing dependencies",
+			},
+			cli.StringFlag{
+				Name:  "template-repo",
+				Usage: "git repository or .zip archive URL to fetch template files from, instead of the embedded ones",
+			},
+			cli.StringFlag{
+				Name:  "template-ref",
+				Usage: "git ref (branch, tag or commit) to use with --template-repo",
+			},
+			cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "print the commands that would run instead of executing them",
+			},
+			cli.BoolFlag{
+				Name:  "force",
+				Usage: "overwrite existing files without prompting",
+			},
+			cli.BoolFlag{
+				Name:  "no-prompt",
+				Usage: "never prompt; skip existing files instead (CI-safe)",
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			name := ctx.Args().First()
+			if name == "" {
+				choice := prompt.Choose("Which template do you want to use?", templateNames())
+				name = templateNames()[choice]
+			}
+
+			tmpl, ok := templateByName(name)
+			if !ok {
+				return fmt.Errorf("unknown template %q, run `platform-starter templates` to see the available ones", name)
+			}
+
+			return apply(resolveTemplate(ctx, tmpl), ctx.String("dir"), ctx.Bool("npm"), ctx.Bool("dry-run"), ctx.Bool("force"), ctx.Bool("no-prompt"))
+		},
+	}
+}
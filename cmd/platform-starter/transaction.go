@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+// backupRoot is where a transaction keeps copies of whatever it
+// overwrites, so a failed apply can be rolled back and a successful one
+// can still be reverted later with `platform-starter restore`.
+const backupRoot = ".platform-starter/backup"
+
+// transaction stages the side effects of a single apply run: every file
+// it's about to overwrite is backed up first, and every file it creates
+// from scratch is remembered so rollback can remove it again. backupDir
+// is rooted at the same --dir the restore subcommand looks under, so
+// the two always agree regardless of the project's working directory.
+type transaction struct {
+	stamp     string
+	backupDir string
+
+	backedUp []string
+	created  []string
+
+	// gitRoot is set to the repository root when this transaction
+	// initializes a fresh git repo there, so rollback knows to remove
+	// it again.
+	gitRoot string
+}
+
+// newTransaction starts a transaction rooted at dir. In dry-run mode the
+// backup directory is never created - there's nothing to back up since
+// nothing gets written - so a dry-run leaves no trace on disk.
+func newTransaction(dir string, dryRun bool) (*transaction, error) {
+	stamp := time.Now().UTC().Format("20060102150405")
+	backupDir := filepath.Join(dir, backupRoot, stamp)
+	if !dryRun {
+		if err := os.MkdirAll(backupDir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	return &transaction{stamp: stamp, backupDir: backupDir}, nil
+}
+
+// backup saves a copy of path, which must already exist, before it's
+// overwritten or removed. Backups are keyed by the encoded absolute
+// path rather than mirrored into a relative directory structure, so a
+// path outside backupDir's ancestry (e.g. a fromRoot file living
+// above the target --dir) can never escape it.
+func (tx *transaction) backup(path string) error {
+	dest := filepath.Join(tx.backupDir, backupKey(path))
+	if err := copyFileContents(path, dest); err != nil {
+		return err
+	}
+
+	tx.backedUp = append(tx.backedUp, path)
+	return nil
+}
+
+// trackCreated records that path didn't exist before this transaction,
+// so rollback knows to remove it rather than restore it.
+func (tx *transaction) trackCreated(path string) {
+	tx.created = append(tx.created, path)
+}
+
+// rollback restores every file this transaction backed up, removes
+// every file it created, and undoes the git repo it may have
+// initialized.
+func (tx *transaction) rollback() error {
+	var firstErr error
+	note := func(err error) {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	for _, path := range tx.backedUp {
+		note(copyFileContents(filepath.Join(tx.backupDir, backupKey(path)), path))
+	}
+
+	for _, path := range tx.created {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			note(err)
+		}
+	}
+
+	if tx.gitRoot != "" {
+		note(os.RemoveAll(filepath.Join(tx.gitRoot, ".git")))
+	}
+
+	return firstErr
+}
+
+// prune removes this transaction's backup directory once it's no longer
+// useful: either nothing was ever backed up, so there's nothing to
+// restore, or rollback already restored everything it holds.
+func (tx *transaction) prune() {
+	if err := os.RemoveAll(tx.backupDir); err != nil {
+		log15.Warn("unable to prune backup directory", "dir", tx.backupDir, "err", err)
+	}
+}
+
+// backupKey turns an absolute path into a flat, collision-free filename
+// that can be safely joined under backupDir without ever climbing back
+// out of it (unlike mirroring the path's directory structure would).
+func backupKey(path string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(filepath.Clean(path)))
+}
+
+func backupKeyToPath(key string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(key)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func copyFileContents(src, dst string) error {
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	fi, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(dst, data, fi.Mode())
+}
+
+// restoreCommand recovers the files backed up by a previous apply,
+// identified by the timestamp of its .platform-starter/backup/<stamp>
+// directory, writing each one back to the absolute path it was backed
+// up from.
+func restoreCommand() cli.Command {
+	return cli.Command{
+		Name:      "restore",
+		Usage:     "restore the files backed up during a previous apply",
+		ArgsUsage: "<timestamp>",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "dir",
+				Usage: "directory of the project",
+				Value: ".",
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			stamp := ctx.Args().First()
+			if stamp == "" {
+				return fmt.Errorf("missing timestamp, run `platform-starter restore <timestamp>`")
+			}
+
+			dir, err := filepath.Abs(ctx.String("dir"))
+			if err != nil {
+				return err
+			}
+
+			backupDir := filepath.Join(dir, backupRoot, stamp)
+			if !isDir(backupDir) {
+				return fmt.Errorf("no backup found for %s in %s", stamp, dir)
+			}
+
+			return filepath.Walk(backupDir, func(path string, fi os.FileInfo, err error) error {
+				if err != nil || fi.IsDir() {
+					return err
+				}
+
+				dest, err := backupKeyToPath(fi.Name())
+				if err != nil {
+					return fmt.Errorf("unrecognized backup entry %s: %s", fi.Name(), err)
+				}
+
+				log15.Info("Restoring", "file", dest)
+				return copyFileContents(path, dest)
+			})
+		},
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestBackupKeyRoundTrip(t *testing.T) {
+	paths := []string{
+		"/home/user/project/.eslintrc.js",
+		"/home/user/project/../outside/.editorconfig",
+		"/home/user/project/sub/dir/file.json",
+	}
+
+	for _, path := range paths {
+		key := backupKey(path)
+		got, err := backupKeyToPath(key)
+		if err != nil {
+			t.Fatalf("backupKeyToPath(%q) returned error: %s", key, err)
+		}
+		if want := filepath.Clean(path); got != want {
+			t.Errorf("backupKeyToPath(backupKey(%q)) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestBackupKeyToPathRejectsInvalidKey(t *testing.T) {
+	if _, err := backupKeyToPath("not valid base64!!"); err == nil {
+		t.Error("backupKeyToPath with invalid base64 should return an error")
+	}
+}